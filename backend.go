@@ -0,0 +1,23 @@
+package cache
+
+import "time"
+
+// Backend is a slower, larger secondary tier a Cache can spill into once its
+// own sizeLimit is exceeded. See WithSecondary and the fs package for a
+// filesystem-backed implementation.
+type Backend interface {
+	Get(key string) ([]byte, error)
+	Put(key string, data []byte, ttl time.Time) error
+	Delete(key string) error
+	Size() int64
+}
+
+// WithSecondary turns the cache into a hierarchical two-tier cache: entries
+// evicted by compact are handed to b instead of being dropped, and a Get
+// miss against the in-memory tier falls through to b before returning
+// ErrNotFound, re-promoting hits back into memory.
+func WithSecondary(b Backend) OptionFunc {
+	return func(c *Cache) {
+		c.secondary = b
+	}
+}
@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memBackend struct {
+	data map[string][]byte
+}
+
+func newMemBackend() *memBackend {
+	return &memBackend{data: map[string][]byte{}}
+}
+
+func (b *memBackend) Get(key string) ([]byte, error) {
+	data, ok := b.data[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+func (b *memBackend) Put(key string, data []byte, ttl time.Time) error {
+	b.data[key] = data
+	return nil
+}
+
+func (b *memBackend) Delete(key string) error {
+	delete(b.data, key)
+	return nil
+}
+
+func (b *memBackend) Size() int64 {
+	return int64(len(b.data))
+}
+
+func TestCache_compact_spills_to_secondary(t *testing.T) {
+	secondary := newMemBackend()
+
+	c := New(context.Background(), WithSecondary(secondary))
+	c.sizeLimit = 10
+	c.size = 10
+
+	c.storage["foo"] = &item{key: "foo", data: []byte("12345"), size: 5}
+	c.pushFront(c.storage["foo"])
+
+	c.Put("bar", []byte("123456"))
+
+	time.Sleep(time.Millisecond * 50) // time for run 'compact'
+
+	_, ok := c.storage["foo"]
+	assert.False(t, ok)
+
+	data, err := secondary.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("12345"), data)
+}
+
+type slowBackend struct {
+	*memBackend
+	putDelay time.Duration
+}
+
+func (b *slowBackend) Put(key string, data []byte, ttl time.Time) error {
+	time.Sleep(b.putDelay)
+	return b.memBackend.Put(key, data, ttl)
+}
+
+func TestCache_compact_does_not_hold_lock_during_secondary_io(t *testing.T) {
+	secondary := &slowBackend{memBackend: newMemBackend(), putDelay: time.Millisecond * 100}
+
+	c := New(context.Background(), WithSecondary(secondary))
+	c.sizeLimit = 10
+	c.size = 10
+
+	c.storage["foo"] = &item{key: "foo", data: []byte("12345"), size: 5}
+	c.pushFront(c.storage["foo"])
+
+	c.Put("bar", []byte("123456")) // pushes over sizeLimit, triggers an async compact
+
+	time.Sleep(time.Millisecond * 20) // compact should be mid-flight, blocked on secondary.Put
+
+	done := make(chan struct{})
+	go func() {
+		c.Get("bar")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Millisecond * 50):
+		t.Fatal("Get blocked on compact's secondary backend I/O")
+	}
+}
+
+type failingBackend struct {
+	*memBackend
+}
+
+func (b *failingBackend) Put(key string, data []byte, ttl time.Time) error {
+	return errors.New("disk full")
+}
+
+func TestCache_compact_secondary_write_error_keeps_primary_record(t *testing.T) {
+	dir := t.TempDir()
+	secondary := &failingBackend{memBackend: newMemBackend()}
+
+	var mu sync.Mutex
+	var events []Event
+
+	c := New(context.Background(), WithPersistence(dir), WithSecondary(secondary), WithOnEvent(func(e Event) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}))
+	c.sizeLimit = 10
+
+	c.Put("foo", []byte("12345"))
+	c.Put("bar", []byte("123456"))
+
+	time.Sleep(time.Millisecond * 50) // time for run 'compact'
+
+	_, ok := c.storage["foo"]
+	assert.False(t, ok, "foo should still be evicted from memory")
+
+	_, err := secondary.Get("foo")
+	assert.Error(t, err, "secondary write failed, so it shouldn't have the key")
+
+	assert.Equal(t, uint64(1), c.Stats().SecondaryErrors)
+
+	mu.Lock()
+	defer mu.Unlock()
+	found := false
+	for _, e := range events {
+		if e.Type == EventSecondaryWriteError && e.Key == "foo" {
+			found = true
+		}
+	}
+	assert.True(t, found, "expected an EventSecondaryWriteError for foo")
+
+	// The primary persistence record was not tombstoned, so "foo" is still
+	// recoverable on restart even though the secondary write failed.
+	c2 := New(context.Background(), WithPersistence(dir))
+	data, err := c2.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("12345"), data)
+}
+
+func TestCache_Get_falls_through_to_secondary(t *testing.T) {
+	secondary := newMemBackend()
+	secondary.data["foo"] = []byte("bar")
+
+	c := New(context.Background(), WithSecondary(secondary))
+
+	data, err := c.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), data)
+
+	// re-promoted into the in-memory tier
+	_, ok := c.storage["foo"]
+	assert.True(t, ok)
+}
+
+func TestCache_Get_secondary_miss(t *testing.T) {
+	c := New(context.Background(), WithSecondary(newMemBackend()))
+
+	_, err := c.Get("foo")
+	assert.Equal(t, ErrNotFound, err)
+}
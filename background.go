@@ -2,39 +2,63 @@ package cache
 
 import (
 	"context"
-	"sort"
 	"sync/atomic"
 	"time"
 )
 
+// compact evicts the least recently used items, oldest first, until the
+// cache size drops back under sizeLimit. The LRU list makes this O(1) per
+// evicted item instead of sorting the whole storage map. If a secondary
+// backend is configured, evicted items are spilled into it instead of being
+// dropped outright.
 func (c *Cache) compact() {
-	s := make(map[int]string)
-	var t []int
-
-	c.mx.RLock()
-	for key, i := range c.storage {
-		n := int(i.lu.UnixNano())
-		s[n] = key
-		t = append(t, n)
+	c.mx.Lock()
+	c.expMx.Lock()
+
+	var evicted []*item
+
+	for c.tail != nil {
+		i := c.tail
+		c.unlink(i)
+		delete(c.storage, i.key)
+		delete(c.expired, i.key)
+
+		evicted = append(evicted, i)
+
+		if atomic.AddInt64(&c.size, -int64(i.size)) < c.sizeLimit {
+			break
+		}
 	}
-	c.mx.RUnlock()
 
-	sort.Ints(t)
+	c.expMx.Unlock()
+	c.mx.Unlock()
 
-	c.mx.Lock()
-	defer c.mx.Unlock()
-	for _, skey := range t {
-		key := s[skey]
-		i, ok := c.storage[key]
-		if !ok {
-			continue
+	for _, i := range evicted {
+		// Only tombstone the primary persistence record once the item is
+		// safely handed off to the secondary backend (or there is none):
+		// if the secondary write fails, leaving the primary record in place
+		// means the value can still be recovered from it on restart instead
+		// of being lost from every tier at once.
+		spilled := true
+		if c.secondary != nil {
+			data, err := c.itemData(i)
+			if err == nil {
+				err = c.secondary.Put(i.key, data, i.ttl)
+			}
+			if err != nil {
+				spilled = false
+				atomic.AddUint64(&c.stats.SecondaryErrors, 1)
+				c.emit(Event{Type: EventSecondaryWriteError, Key: i.key, Size: i.size, Reason: err.Error()})
+			}
 		}
-		delete(c.storage, key)
-		atomic.AddInt64(&c.size, -int64(len(i.data)))
 
-		if atomic.LoadInt64(&c.size) < c.sizeLimit {
-			return
+		if spilled && c.persist != nil {
+			c.persist.delete(i.key)
 		}
+
+		atomic.AddUint64(&c.stats.Evictions, 1)
+		atomic.AddUint64(&c.stats.ItemCount, ^uint64(0))
+		c.emit(Event{Type: EventEvict, Key: i.key, Size: i.size, Reason: "size_limit"})
 	}
 }
 
@@ -72,6 +96,7 @@ func (c *Cache) clear(ctx context.Context) {
 
 		c.expMx.Lock()
 		c.mx.Lock()
+		var expired []*item
 		for key := range c.expired {
 			delete(c.expired, key)
 			i, ok := c.storage[key]
@@ -79,11 +104,23 @@ func (c *Cache) clear(ctx context.Context) {
 				continue
 			}
 			delete(c.storage, key)
-			atomic.AddInt64(&c.size, -int64(len(i.data)))
+			c.unlink(i)
+			atomic.AddInt64(&c.size, -int64(i.size))
+			expired = append(expired, i)
 		}
 		c.mx.Unlock()
 		c.expMx.Unlock()
 
+		for _, i := range expired {
+			if c.persist != nil {
+				c.persist.delete(i.key)
+			}
+
+			atomic.AddUint64(&c.stats.Expirations, 1)
+			atomic.AddUint64(&c.stats.ItemCount, ^uint64(0))
+			c.emit(Event{Type: EventExpire, Key: i.key, Size: i.size})
+		}
+
 		time.Sleep(c.clearExpireTimout)
 	}
 }
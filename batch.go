@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+type batchOpKind int
+
+const (
+	batchOpPut batchOpKind = iota
+	batchOpPutWithTTL
+	batchOpDelete
+)
+
+type batchOp struct {
+	kind batchOpKind
+	key  string
+	data []byte
+	ttl  time.Duration
+}
+
+// preparedOp is a batchOp that has already been appended to the persistence
+// log (if enabled) and is ready to be applied to storage under a single lock
+// acquisition. item is nil for a delete; a delete of a key that is not in
+// storage is not prepared at all.
+type preparedOp struct {
+	kind batchOpKind
+	key  string
+	item *item
+}
+
+// Batch accumulates Put, PutWithTTL and Delete operations to be applied to a
+// Cache as a single unit via Cache.Write.
+type Batch struct {
+	ops []batchOp
+}
+
+// NewBatch returns an empty Batch.
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+func (b *Batch) Put(key string, data []byte) {
+	b.ops = append(b.ops, batchOp{kind: batchOpPut, key: key, data: data})
+}
+
+func (b *Batch) PutWithTTL(key string, data []byte, ttl time.Duration) {
+	b.ops = append(b.ops, batchOp{kind: batchOpPutWithTTL, key: key, data: data, ttl: ttl})
+}
+
+func (b *Batch) Delete(key string) {
+	b.ops = append(b.ops, batchOp{kind: batchOpDelete, key: key})
+}
+
+// Write applies b's operations atomically: every key in the batch becomes
+// visible to Get/Has at once, under a single lock acquisition instead of one
+// per operation. If the batch pushes the cache over sizeLimit, a single
+// compaction pass runs once the batch is applied.
+//
+// As with Put and PutWithTTL, each op's persistence append happens before
+// any lock is taken, so a large batch's disk I/O never blocks concurrent
+// Get/Put/Delete callers.
+func (c *Cache) Write(b *Batch) error {
+	if b == nil || len(b.ops) == 0 {
+		return nil
+	}
+
+	prepared := make([]preparedOp, 0, len(b.ops))
+
+	for _, op := range b.ops {
+		switch op.kind {
+		case batchOpPut, batchOpPutWithTTL:
+			if int64(len(op.data)) >= c.sizeLimit {
+				continue
+			}
+
+			i := &item{
+				key:  op.key,
+				data: op.data,
+				size: len(op.data),
+				lu:   time.Now(),
+			}
+			if op.kind == batchOpPutWithTTL {
+				i.ttl = time.Now().Add(op.ttl)
+			}
+
+			if c.persist != nil {
+				fileID, offset, err := c.persist.put(op.key, op.data, i.ttl)
+				if err == nil {
+					i.fileID, i.offset = fileID, offset
+				}
+			}
+
+			prepared = append(prepared, preparedOp{kind: op.kind, key: op.key, item: i})
+
+		case batchOpDelete:
+			c.mx.RLock()
+			_, ok := c.storage[op.key]
+			c.mx.RUnlock()
+			if !ok {
+				continue
+			}
+
+			if c.persist != nil {
+				c.persist.delete(op.key)
+			}
+
+			prepared = append(prepared, preparedOp{kind: batchOpDelete, key: op.key})
+		}
+	}
+
+	c.mx.Lock()
+	c.expMx.Lock()
+
+	var events []Event
+
+	for _, p := range prepared {
+		switch p.kind {
+		case batchOpPut, batchOpPutWithTTL:
+			_, existed := c.storage[p.key]
+			if old, ok := c.storage[p.key]; ok {
+				c.unlink(old)
+				atomic.AddInt64(&c.size, -int64(old.size))
+			}
+			c.storage[p.key] = p.item
+			c.pushFront(p.item)
+			delete(c.expired, p.key)
+			atomic.AddInt64(&c.size, int64(p.item.size))
+
+			if !existed {
+				atomic.AddUint64(&c.stats.ItemCount, 1)
+			}
+			atomic.AddUint64(&c.stats.BytesIn, uint64(p.item.size))
+			events = append(events, Event{Type: EventPut, Key: p.key, Size: p.item.size})
+
+		case batchOpDelete:
+			old, ok := c.storage[p.key]
+			if !ok {
+				continue
+			}
+
+			delete(c.storage, p.key)
+			c.unlink(old)
+			atomic.AddInt64(&c.size, -int64(old.size))
+
+			atomic.AddUint64(&c.stats.ItemCount, ^uint64(0))
+			events = append(events, Event{Type: EventDelete, Key: p.key, Size: old.size})
+		}
+	}
+
+	overLimit := atomic.LoadInt64(&c.size) > c.sizeLimit
+
+	c.expMx.Unlock()
+	c.mx.Unlock()
+
+	for _, e := range events {
+		c.emit(e)
+	}
+
+	if overLimit {
+		c.compact()
+	}
+
+	return nil
+}
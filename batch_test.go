@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_Write(t *testing.T) {
+	c := New(context.Background())
+
+	b := NewBatch()
+	b.Put("foo", []byte{0x10})
+	b.PutWithTTL("bar", []byte{0x20}, time.Hour)
+
+	err := c.Write(b)
+	assert.NoError(t, err)
+
+	data, err := c.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x10}, data)
+
+	data, err = c.Get("bar")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x20}, data)
+}
+
+func TestCache_Write_delete(t *testing.T) {
+	c := New(context.Background())
+	c.Put("foo", []byte{0x10})
+
+	b := NewBatch()
+	b.Delete("foo")
+	b.Put("bar", []byte{0x20})
+
+	err := c.Write(b)
+	assert.NoError(t, err)
+
+	_, err = c.Get("foo")
+	assert.Equal(t, ErrNotFound, err)
+
+	data, err := c.Get("bar")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x20}, data)
+}
+
+func TestCache_Write_empty(t *testing.T) {
+	c := New(context.Background())
+
+	assert.NoError(t, c.Write(nil))
+	assert.NoError(t, c.Write(NewBatch()))
+}
+
+func TestCache_Write_triggers_compact(t *testing.T) {
+	c := New(context.Background())
+	c.sizeLimit = 10
+
+	b := NewBatch()
+	b.Put("foo", []byte("12345"))
+	b.Put("bar", []byte("12345"))
+	b.Put("baz", []byte("12345"))
+
+	err := c.Write(b)
+	assert.NoError(t, err)
+
+	assert.True(t, c.size < c.sizeLimit)
+	assert.Equal(t, 1, len(c.storage))
+}
+
+func TestCache_Write_with_persistence_survives_restart(t *testing.T) {
+	dir := t.TempDir()
+
+	c := New(context.Background(), WithPersistence(dir))
+
+	b := NewBatch()
+	b.Put("foo", []byte("bar"))
+	b.Delete("foo") // no-op: "foo" isn't in storage yet when the batch is prepared
+	b.PutWithTTL("baz", []byte("qux"), time.Hour)
+
+	err := c.Write(b)
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.Sync())
+
+	c2 := New(context.Background(), WithPersistence(dir))
+
+	data, err := c2.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), data)
+
+	data, err = c2.Get("baz")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("qux"), data)
+}
+
+func TestCache_Write_overwrite_accounts_size(t *testing.T) {
+	c := New(context.Background())
+	c.Put("foo", []byte("12345"))
+
+	b := NewBatch()
+	b.Put("foo", []byte("12"))
+
+	err := c.Write(b)
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(2), c.size)
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"errors"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -18,6 +19,9 @@ var (
 	ErrNotFound = errors.New("not found")
 )
 
+// ErrNoPersistence is returned by Sync when the cache was opened without WithPersistence.
+var ErrNoPersistence = errors.New("cache: persistence is not enabled")
+
 type GetCallback func(string) ([]byte, error)
 
 type OptionFunc func(c *Cache)
@@ -40,15 +44,65 @@ func WithSizeLimit(l int64) OptionFunc {
 	}
 }
 
+// WithPersistence makes the cache durable: every Put, PutWithTTL and Delete
+// is appended to a bitcask-style log under dir, and New replays that log to
+// rebuild the cache's contents before returning.
+func WithPersistence(dir string) OptionFunc {
+	return func(c *Cache) {
+		c.persistDir = dir
+	}
+}
+
+// WithSegmentSize sets the size at which the active persistence segment is
+// rotated to a new file. Only meaningful together with WithPersistence.
+func WithSegmentSize(n int64) OptionFunc {
+	return func(c *Cache) {
+		c.persistSegmentSize = n
+	}
+}
+
+// WithMergeInterval sets how often the persistence merge goroutine rewrites
+// live records into a fresh segment. Only meaningful together with
+// WithPersistence.
+func WithMergeInterval(t time.Duration) OptionFunc {
+	return func(c *Cache) {
+		c.mergeInterval = t
+	}
+}
+
+// WithSingleflight controls whether concurrent GetOrNew/GetOrNewWithTTL
+// misses on the same key share a single callback invocation. Enabled by
+// default; pass false to let every caller invoke its callback independently.
+func WithSingleflight(enabled bool) OptionFunc {
+	return func(c *Cache) {
+		c.singleflight = enabled
+	}
+}
+
+// item is a node of the cache's intrusive LRU list. prev/next are only
+// ever read or written while c.mx is held.
 type item struct {
+	key  string
 	data []byte
+	size int
 	ttl  time.Time
 	lu   time.Time
+
+	prev *item
+	next *item
+
+	// fileID/offset locate this item's record on disk when persistence is
+	// enabled. data is nil only for an item rebuilt from a hint file, until
+	// its value is read off disk at least once.
+	fileID int
+	offset int64
 }
 
 type Cache struct {
 	mx      sync.RWMutex
 	storage map[string]*item
+	head    *item
+	tail    *item
 
 	expMx   sync.RWMutex
 	expired map[string]struct{}
@@ -57,6 +111,21 @@ type Cache struct {
 	checkExpireTimout time.Duration
 	sizeLimit         int64
 	size              int64
+
+	persistDir         string
+	persistSegmentSize int64
+	mergeInterval      time.Duration
+	persist            *persistence
+
+	singleflight bool
+	sfMx         sync.Mutex
+	sfCalls      map[string]*call
+
+	secondary Backend
+
+	stats                Stats
+	onEvent              func(Event)
+	metricsResetInterval time.Duration
 }
 
 func New(ctx context.Context, options ...OptionFunc) *Cache {
@@ -66,36 +135,171 @@ func New(ctx context.Context, options ...OptionFunc) *Cache {
 		clearExpireTimout: defaultClearExpireTimeout,
 		checkExpireTimout: defaultCheckExpireTimeout,
 		sizeLimit:         defaultSizeLimit,
+
+		persistSegmentSize: defaultSegmentSizeLimit,
+		mergeInterval:      defaultMergeInterval,
+
+		singleflight: true,
+		sfCalls:      map[string]*call{},
 	}
 
 	for _, o := range options {
 		o(c)
 	}
 
+	if c.persistDir != "" {
+		p, err := openPersistence(c.persistDir, c.persistSegmentSize)
+		if err == nil {
+			storage, size, err := p.rebuild()
+			if err == nil {
+				c.storage = storage
+				c.size = size
+				for _, i := range sortedByLu(storage) {
+					c.pushFront(i)
+				}
+			}
+			c.persist = p
+			go c.mergeLoop(ctx)
+		}
+	}
+
+	if c.metricsResetInterval > 0 {
+		go c.resetStatsLoop(ctx)
+	}
+
 	go c.clear(ctx)
 	go c.scanExpired(ctx)
 
 	return c
 }
 
+// sortedByLu returns storage's items ordered oldest-last-used first, so
+// pushing them onto a fresh LRU list in this order reproduces the recency
+// order they had before a restart.
+func sortedByLu(storage map[string]*item) []*item {
+	items := make([]*item, 0, len(storage))
+	for _, i := range storage {
+		items = append(items, i)
+	}
+
+	sort.Slice(items, func(a, b int) bool {
+		return items[a].lu.Before(items[b].lu)
+	})
+
+	return items
+}
+
+// pushFront inserts i as the most recently used item. The caller must hold c.mx.
+func (c *Cache) pushFront(i *item) {
+	i.prev = nil
+	i.next = c.head
+
+	if c.head != nil {
+		c.head.prev = i
+	}
+	c.head = i
+
+	if c.tail == nil {
+		c.tail = i
+	}
+}
+
+// unlink removes i from the LRU list. The caller must hold c.mx.
+func (c *Cache) unlink(i *item) {
+	if i.prev != nil {
+		i.prev.next = i.next
+	} else {
+		c.head = i.next
+	}
+
+	if i.next != nil {
+		i.next.prev = i.prev
+	} else {
+		c.tail = i.prev
+	}
+
+	i.prev = nil
+	i.next = nil
+}
+
+// moveToFront marks i as the most recently used item. The caller must hold c.mx.
+func (c *Cache) moveToFront(i *item) {
+	if c.head == i {
+		return
+	}
+
+	c.unlink(i)
+	c.pushFront(i)
+}
+
 func (c *Cache) Get(key string) ([]byte, error) {
-	c.mx.RLock()
+	c.mx.Lock()
+
 	i, ok := c.storage[key]
-	c.mx.RUnlock()
 	if !ok {
-		return nil, ErrNotFound
+		c.mx.Unlock()
+		return c.getFromSecondary(key)
 	}
 
 	if !i.ttl.IsZero() && i.ttl.Before(time.Now()) {
+		c.mx.Unlock()
+
 		c.expMx.Lock()
 		c.expired[key] = struct{}{}
 		c.expMx.Unlock()
+
+		atomic.AddUint64(&c.stats.Misses, 1)
+
 		return nil, ErrNotFound
 	}
 
 	i.lu = time.Now()
+	c.moveToFront(i)
+	data := i.data
+	persist := c.persist
+	fileID, offset := i.fileID, i.offset
+
+	c.mx.Unlock()
+
+	if data == nil && persist != nil {
+		var err error
+		data, err = persist.read(fileID, offset)
+		if err != nil {
+			atomic.AddUint64(&c.stats.Misses, 1)
+			return nil, ErrNotFound
+		}
+
+		c.mx.Lock()
+		i.data = data
+		c.mx.Unlock()
+	}
+
+	atomic.AddUint64(&c.stats.Hits, 1)
+	atomic.AddUint64(&c.stats.BytesOut, uint64(len(data)))
+
+	return data, nil
+}
+
+// getFromSecondary is the fallback path for a Get that missed the in-memory
+// tier: it consults the secondary backend, if any, and re-promotes a hit.
+func (c *Cache) getFromSecondary(key string) ([]byte, error) {
+	if c.secondary == nil {
+		atomic.AddUint64(&c.stats.Misses, 1)
+		return nil, ErrNotFound
+	}
+
+	data, err := c.secondary.Get(key)
+	if err != nil {
+		atomic.AddUint64(&c.stats.Misses, 1)
+		return nil, ErrNotFound
+	}
+
+	c.Put(key, data)
+
+	atomic.AddUint64(&c.stats.Hits, 1)
+	atomic.AddUint64(&c.stats.BytesOut, uint64(len(data)))
 
-	return i.data, nil
+	return data, nil
 }
 
 func (c *Cache) GetOrNew(key string, cb GetCallback) ([]byte, error) {
@@ -104,14 +308,30 @@ func (c *Cache) GetOrNew(key string, cb GetCallback) ([]byte, error) {
 		return data, nil
 	}
 
-	data, err = cb(key)
-	if err != nil {
-		return nil, err
+	if !c.singleflight {
+		data, err = cb(key)
+		if err != nil {
+			return nil, err
+		}
+
+		c.Put(key, data)
+
+		return data, nil
 	}
 
-	c.Put(key, data)
+	cl, owner := c.startCall(key)
+	if !owner {
+		cl.wg.Wait()
+		return cl.data, cl.err
+	}
 
-	return data, nil
+	data, err = cb(key)
+	if err == nil {
+		c.Put(key, data)
+	}
+	c.finishCall(key, cl, data, err)
+
+	return data, err
 }
 
 func (c *Cache) GetOrNewWithTTL(key string, ttl time.Duration, cb GetCallback) ([]byte, error) {
@@ -120,14 +340,30 @@ func (c *Cache) GetOrNewWithTTL(key string, ttl time.Duration, cb GetCallback) (
 		return data, nil
 	}
 
-	data, err = cb(key)
-	if err != nil {
-		return nil, err
+	if !c.singleflight {
+		data, err = cb(key)
+		if err != nil {
+			return nil, err
+		}
+
+		c.PutWithTTL(key, data, ttl)
+
+		return data, nil
 	}
 
-	c.PutWithTTL(key, data, ttl)
+	cl, owner := c.startCall(key)
+	if !owner {
+		cl.wg.Wait()
+		return cl.data, cl.err
+	}
 
-	return data, nil
+	data, err = cb(key)
+	if err == nil {
+		c.PutWithTTL(key, data, ttl)
+	}
+	c.finishCall(key, cl, data, err)
+
+	return data, err
 }
 
 func (c *Cache) Has(key string) bool {
@@ -144,16 +380,25 @@ func (c *Cache) Has(key string) bool {
 
 func (c *Cache) Delete(key string) {
 	c.mx.Lock()
-	defer c.mx.Unlock()
 
 	i, ok := c.storage[key]
 	if !ok {
+		c.mx.Unlock()
 		return
 	}
 
 	delete(c.storage, key)
+	c.unlink(i)
+	atomic.AddInt64(&c.size, -int64(i.size))
+
+	c.mx.Unlock()
+
+	if c.persist != nil {
+		c.persist.delete(key)
+	}
 
-	atomic.AddInt64(&c.size, -int64(len(i.data)))
+	atomic.AddUint64(&c.stats.ItemCount, ^uint64(0))
+	c.emit(Event{Type: EventDelete, Key: key, Size: i.size})
 }
 
 func (c *Cache) Put(key string, data []byte) {
@@ -161,16 +406,38 @@ func (c *Cache) Put(key string, data []byte) {
 		return
 	}
 
-	c.mx.Lock()
-	c.expMx.Lock()
-	c.storage[key] = &item{
+	i := &item{
+		key:  key,
 		data: data,
+		size: len(data),
 		lu:   time.Now(),
 	}
+
+	if c.persist != nil {
+		fileID, offset, err := c.persist.put(key, data, time.Time{})
+		if err == nil {
+			i.fileID, i.offset = fileID, offset
+		}
+	}
+
+	c.mx.Lock()
+	c.expMx.Lock()
+	_, existed := c.storage[key]
+	if old, ok := c.storage[key]; ok {
+		c.unlink(old)
+	}
+	c.storage[key] = i
+	c.pushFront(i)
 	delete(c.expired, key)
 	c.expMx.Unlock()
 	c.mx.Unlock()
 
+	if !existed {
+		atomic.AddUint64(&c.stats.ItemCount, 1)
+	}
+	atomic.AddUint64(&c.stats.BytesIn, uint64(len(data)))
+	c.emit(Event{Type: EventPut, Key: key, Size: len(data)})
+
 	n := atomic.AddInt64(&c.size, int64(len(data)))
 	if n > c.sizeLimit {
 		go c.compact()
@@ -182,19 +449,52 @@ func (c *Cache) PutWithTTL(key string, data []byte, ttl time.Duration) {
 		return
 	}
 
-	c.mx.Lock()
-	c.expMx.Lock()
-	c.storage[key] = &item{
+	ttlAt := time.Now().Add(ttl)
+	i := &item{
+		key:  key,
 		data: data,
-		ttl:  time.Now().Add(ttl),
+		size: len(data),
+		ttl:  ttlAt,
 		lu:   time.Now(),
 	}
+
+	if c.persist != nil {
+		fileID, offset, err := c.persist.put(key, data, ttlAt)
+		if err == nil {
+			i.fileID, i.offset = fileID, offset
+		}
+	}
+
+	c.mx.Lock()
+	c.expMx.Lock()
+	_, existed := c.storage[key]
+	if old, ok := c.storage[key]; ok {
+		c.unlink(old)
+	}
+	c.storage[key] = i
+	c.pushFront(i)
 	delete(c.expired, key)
 	c.expMx.Unlock()
 	c.mx.Unlock()
 
+	if !existed {
+		atomic.AddUint64(&c.stats.ItemCount, 1)
+	}
+	atomic.AddUint64(&c.stats.BytesIn, uint64(len(data)))
+	c.emit(Event{Type: EventPut, Key: key, Size: len(data)})
+
 	n := atomic.AddInt64(&c.size, int64(len(data)))
 	if n > c.sizeLimit {
 		go c.compact()
 	}
 }
+
+// Sync flushes the active persistence segment to disk. It returns
+// ErrNoPersistence if the cache was not opened with WithPersistence.
+func (c *Cache) Sync() error {
+	if c.persist == nil {
+		return ErrNoPersistence
+	}
+
+	return c.persist.sync()
+}
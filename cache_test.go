@@ -270,14 +270,14 @@ func TestCache_clear_with_check(t *testing.T) {
 
 func TestPut_compact(t *testing.T) {
 	c := New(context.Background())
-	c.sizeLimit = 20
-	c.size = 15
 
-	now := time.Now()
+	// oldest first, so the LRU list tail is "3", then "2", then "1"
+	c.Put("3", []byte("12345"))
+	c.Put("2", []byte("12345"))
+	c.Put("1", []byte("12345"))
 
-	c.storage["1"] = &item{data: []byte("12345"), lu: now.Add(time.Second * -1)}
-	c.storage["2"] = &item{data: []byte("12345"), lu: now.Add(time.Second * -2)}
-	c.storage["3"] = &item{data: []byte("12345"), lu: now.Add(time.Second * -3)}
+	c.sizeLimit = 20
+	c.size = 15
 
 	c.Put("5", []byte("1234"))
 
@@ -301,16 +301,40 @@ func TestPut_compact(t *testing.T) {
 	assert.True(t, ok)
 }
 
-func TestPutWithTTL_compact(t *testing.T) {
+func TestCache_Get_moves_to_front(t *testing.T) {
 	c := New(context.Background())
+
+	c.Put("1", []byte("12345"))
+	c.Put("2", []byte("12345"))
+	c.Put("3", []byte("12345"))
+
+	// touching "1" should move it to the front, leaving "2" as the LRU tail
+	_, err := c.Get("1")
+	assert.NoError(t, err)
+
 	c.sizeLimit = 20
 	c.size = 15
 
-	now := time.Now()
+	c.Put("5", []byte("1234567890")) // forces a compact pass
+
+	time.Sleep(time.Millisecond * 50) // time for run 'compact'
 
-	c.storage["1"] = &item{data: []byte("12345"), lu: now.Add(time.Second * -1)}
-	c.storage["2"] = &item{data: []byte("12345"), lu: now.Add(time.Second * -2)}
-	c.storage["3"] = &item{data: []byte("12345"), lu: now.Add(time.Second * -3)}
+	_, ok := c.storage["1"]
+	assert.True(t, ok)
+	_, ok = c.storage["2"]
+	assert.False(t, ok)
+}
+
+func TestPutWithTTL_compact(t *testing.T) {
+	c := New(context.Background())
+
+	// oldest first, so the LRU list tail is "3", then "2", then "1"
+	c.Put("3", []byte("12345"))
+	c.Put("2", []byte("12345"))
+	c.Put("1", []byte("12345"))
+
+	c.sizeLimit = 20
+	c.size = 15
 
 	c.PutWithTTL("5", []byte("1234"), time.Hour)
 
@@ -0,0 +1,227 @@
+// Package fs provides FSBackend, a filesystem-backed implementation of
+// cache.Backend suitable for spilling large or cold values out of an
+// in-memory cache onto local disk.
+package fs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultSweepInterval = time.Hour
+
+	// valueHeaderSize is the 8-byte unix-nano ttl deadline prefixed to every
+	// stored value (0 means no expiry).
+	valueHeaderSize = 8
+)
+
+var errNotFound = os.ErrNotExist
+
+type OptionFunc func(b *FSBackend)
+
+// WithSweepInterval sets how often the background sweeper checks the
+// backend's on-disk size against its cap.
+func WithSweepInterval(t time.Duration) OptionFunc {
+	return func(b *FSBackend) {
+		b.sweepInterval = t
+	}
+}
+
+// FSBackend stores values as individual files under dir, sharded into
+// subdirectories by a hash of the key so no single directory gets too large.
+// It enforces sizeLimit bytes on a timer by deleting the least recently
+// written files first.
+type FSBackend struct {
+	dir       string
+	sizeLimit int64
+	size      int64
+
+	sweepInterval time.Duration
+}
+
+// New opens (creating if necessary) a filesystem backend rooted at dir.
+// sizeLimit is a human-readable byte size such as "64MB" or "2GB".
+func New(ctx context.Context, dir string, sizeLimit string, options ...OptionFunc) (*FSBackend, error) {
+	limit, err := ParseSize(sizeLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	b := &FSBackend{
+		dir:           dir,
+		sizeLimit:     limit,
+		sweepInterval: defaultSweepInterval,
+	}
+
+	for _, o := range options {
+		o(b)
+	}
+
+	if size, err := b.walkSize(); err == nil {
+		atomic.StoreInt64(&b.size, size)
+	}
+
+	go b.sweepLoop(ctx)
+
+	return b, nil
+}
+
+func (b *FSBackend) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+
+	return filepath.Join(b.dir, name[0:2], name[2:4], name)
+}
+
+func (b *FSBackend) Get(key string) ([]byte, error) {
+	buf, err := os.ReadFile(b.path(key))
+	if err != nil {
+		return nil, errNotFound
+	}
+
+	if len(buf) < valueHeaderSize {
+		return nil, errNotFound
+	}
+
+	ttlNano := int64(binary.BigEndian.Uint64(buf[:valueHeaderSize]))
+	if ttlNano != 0 && time.Unix(0, ttlNano).Before(time.Now()) {
+		b.Delete(key)
+		return nil, errNotFound
+	}
+
+	return buf[valueHeaderSize:], nil
+}
+
+func (b *FSBackend) Put(key string, data []byte, ttl time.Time) error {
+	p := b.path(key)
+
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	var ttlNano int64
+	if !ttl.IsZero() {
+		ttlNano = ttl.UnixNano()
+	}
+
+	buf := make([]byte, valueHeaderSize+len(data))
+	binary.BigEndian.PutUint64(buf[:valueHeaderSize], uint64(ttlNano))
+	copy(buf[valueHeaderSize:], data)
+
+	if prev, err := os.Stat(p); err == nil {
+		atomic.AddInt64(&b.size, -prev.Size())
+	}
+
+	if err := os.WriteFile(p, buf, 0o644); err != nil {
+		return err
+	}
+	atomic.AddInt64(&b.size, int64(len(buf)))
+
+	return nil
+}
+
+func (b *FSBackend) Delete(key string) error {
+	p := b.path(key)
+
+	if info, err := os.Stat(p); err == nil {
+		atomic.AddInt64(&b.size, -info.Size())
+	}
+
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (b *FSBackend) Size() int64 {
+	return atomic.LoadInt64(&b.size)
+}
+
+type fileEntry struct {
+	path  string
+	size  int64
+	mtime time.Time
+}
+
+func (b *FSBackend) walkSize() (int64, error) {
+	var total int64
+
+	err := filepath.WalkDir(b.dir, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		total += info.Size()
+		return nil
+	})
+
+	return total, err
+}
+
+// sweep deletes the least recently written files until the backend's total
+// size is back under sizeLimit.
+func (b *FSBackend) sweep() {
+	var files []fileEntry
+	var total int64
+
+	filepath.WalkDir(b.dir, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, fileEntry{path: path, size: info.Size(), mtime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+
+	if total <= b.sizeLimit {
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].mtime.Before(files[j].mtime)
+	})
+
+	for _, f := range files {
+		if total <= b.sizeLimit {
+			break
+		}
+		if err := os.Remove(f.path); err != nil {
+			continue
+		}
+		total -= f.size
+		atomic.AddInt64(&b.size, -f.size)
+	}
+}
+
+func (b *FSBackend) sweepLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.sweepInterval):
+		}
+
+		b.sweep()
+	}
+}
@@ -0,0 +1,82 @@
+package fs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFSBackend_Put_Get(t *testing.T) {
+	dir := t.TempDir()
+
+	b, err := New(context.Background(), dir, "1MB")
+	assert.NoError(t, err)
+
+	err = b.Put("foo", []byte("bar"), time.Time{})
+	assert.NoError(t, err)
+
+	data, err := b.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), data)
+	assert.Equal(t, int64(len("bar")+valueHeaderSize), b.Size())
+}
+
+func TestFSBackend_Get_not_found(t *testing.T) {
+	dir := t.TempDir()
+
+	b, err := New(context.Background(), dir, "1MB")
+	assert.NoError(t, err)
+
+	_, err = b.Get("missing")
+	assert.Error(t, err)
+}
+
+func TestFSBackend_Get_expired(t *testing.T) {
+	dir := t.TempDir()
+
+	b, err := New(context.Background(), dir, "1MB")
+	assert.NoError(t, err)
+
+	err = b.Put("foo", []byte("bar"), time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+
+	_, err = b.Get("foo")
+	assert.Error(t, err)
+}
+
+func TestFSBackend_Delete(t *testing.T) {
+	dir := t.TempDir()
+
+	b, err := New(context.Background(), dir, "1MB")
+	assert.NoError(t, err)
+
+	b.Put("foo", []byte("bar"), time.Time{})
+	err = b.Delete("foo")
+	assert.NoError(t, err)
+
+	_, err = b.Get("foo")
+	assert.Error(t, err)
+	assert.Equal(t, int64(0), b.Size())
+}
+
+func TestFSBackend_sweep_enforces_cap(t *testing.T) {
+	dir := t.TempDir()
+
+	b, err := New(context.Background(), dir, "15B", WithSweepInterval(time.Millisecond*20))
+	assert.NoError(t, err)
+
+	b.Put("foo", []byte("12345"), time.Time{})
+	time.Sleep(time.Millisecond * 10)
+	b.Put("bar", []byte("12345"), time.Time{})
+
+	time.Sleep(time.Millisecond * 50)
+
+	_, err = b.Get("foo")
+	assert.Error(t, err)
+
+	data, err := b.Get("bar")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("12345"), data)
+}
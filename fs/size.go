@@ -0,0 +1,48 @@
+package fs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// unit suffixes, checked longest-first so "MB" isn't matched as a bare "B".
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"GB", 1024 * 1024 * 1024},
+	{"MB", 1024 * 1024},
+	{"KB", 1024},
+	{"B", 1},
+}
+
+// ParseSize parses a human-readable byte size such as "64MB", "2GB" or
+// "1024" (bytes, no suffix) into its value in bytes.
+func ParseSize(s string) (int64, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(s))
+	if trimmed == "" {
+		return 0, fmt.Errorf("parse size: empty string")
+	}
+
+	for _, u := range sizeUnits {
+		if !strings.HasSuffix(trimmed, u.suffix) {
+			continue
+		}
+
+		numPart := strings.TrimSpace(strings.TrimSuffix(trimmed, u.suffix))
+		n, err := strconv.ParseFloat(numPart, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse size %q: %w", s, err)
+		}
+
+		return int64(n * float64(u.multiplier)), nil
+	}
+
+	n, err := strconv.ParseInt(trimmed, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse size %q: %w", s, err)
+	}
+
+	return n, nil
+}
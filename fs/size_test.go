@@ -0,0 +1,33 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"0":     0,
+		"1024":  1024,
+		"1B":    1,
+		"1KB":   1024,
+		"64MB":  64 * 1024 * 1024,
+		"2GB":   2 * 1024 * 1024 * 1024,
+		"1.5MB": int64(1.5 * 1024 * 1024),
+	}
+
+	for in, want := range cases {
+		got, err := ParseSize(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+}
+
+func TestParseSize_invalid(t *testing.T) {
+	_, err := ParseSize("")
+	assert.Error(t, err)
+
+	_, err = ParseSize("abc")
+	assert.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"strings"
+	"time"
+)
+
+// itemData returns i's value, reading it from the persistence log if it
+// hasn't been loaded into memory yet. Callers that reach i through c.storage
+// must hold at least c.mx.RLock(); it is also safe to call on an item that
+// has already been unlinked and removed from c.storage without holding the
+// lock, since no other goroutine can still reach it through the cache.
+func (c *Cache) itemData(i *item) ([]byte, error) {
+	if i.data != nil || c.persist == nil {
+		return i.data, nil
+	}
+
+	return c.persist.read(i.fileID, i.offset)
+}
+
+// Keys returns the keys of every non-expired item currently in the cache.
+func (c *Cache) Keys() []string {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+
+	now := time.Now()
+
+	keys := make([]string, 0, len(c.storage))
+	for key, i := range c.storage {
+		if !i.ttl.IsZero() && i.ttl.Before(now) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// Len returns the number of non-expired items currently in the cache.
+func (c *Cache) Len() int {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+
+	now := time.Now()
+
+	n := 0
+	for _, i := range c.storage {
+		if !i.ttl.IsZero() && i.ttl.Before(now) {
+			continue
+		}
+		n++
+	}
+
+	return n
+}
+
+// Fold calls fn for every non-expired item in the cache, stopping and
+// returning fn's error as soon as it returns one.
+func (c *Cache) Fold(fn func(key string, data []byte) error) error {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+
+	now := time.Now()
+
+	for key, i := range c.storage {
+		if !i.ttl.IsZero() && i.ttl.Before(now) {
+			continue
+		}
+
+		data, err := c.itemData(i)
+		if err != nil {
+			continue
+		}
+
+		if err := fn(key, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Range calls fn for every non-expired item whose key starts with prefix,
+// stopping as soon as fn returns false.
+func (c *Cache) Range(prefix string, fn func(key string, data []byte) bool) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+
+	now := time.Now()
+
+	for key, i := range c.storage {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if !i.ttl.IsZero() && i.ttl.Before(now) {
+			continue
+		}
+
+		data, err := c.itemData(i)
+		if err != nil {
+			continue
+		}
+
+		if !fn(key, data) {
+			return
+		}
+	}
+}
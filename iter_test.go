@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_Keys(t *testing.T) {
+	c := New(context.Background())
+
+	c.Put("foo", []byte{0x10})
+	c.Put("bar", []byte{0x20})
+	c.PutWithTTL("baz", []byte{0x30}, -time.Second)
+
+	keys := c.Keys()
+	sort.Strings(keys)
+	assert.Equal(t, []string{"bar", "foo"}, keys)
+}
+
+func TestCache_Len(t *testing.T) {
+	c := New(context.Background())
+
+	assert.Equal(t, 0, c.Len())
+
+	c.Put("foo", []byte{0x10})
+	c.Put("bar", []byte{0x20})
+	c.PutWithTTL("baz", []byte{0x30}, -time.Second)
+
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestCache_Fold(t *testing.T) {
+	c := New(context.Background())
+
+	c.Put("foo", []byte{0x10})
+	c.Put("bar", []byte{0x20})
+	c.PutWithTTL("baz", []byte{0x30}, -time.Second)
+
+	got := map[string][]byte{}
+	err := c.Fold(func(key string, data []byte) error {
+		got[key] = data
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"foo": {0x10}, "bar": {0x20}}, got)
+}
+
+func TestCache_Fold_stops_on_error(t *testing.T) {
+	c := New(context.Background())
+
+	c.Put("foo", []byte{0x10})
+	c.Put("bar", []byte{0x20})
+
+	boom := assert.AnError
+	err := c.Fold(func(key string, data []byte) error {
+		return boom
+	})
+	assert.Equal(t, boom, err)
+}
+
+func TestCache_Range(t *testing.T) {
+	c := New(context.Background())
+
+	c.Put("user:1", []byte{0x10})
+	c.Put("user:2", []byte{0x20})
+	c.Put("order:1", []byte{0x30})
+
+	got := map[string][]byte{}
+	c.Range("user:", func(key string, data []byte) bool {
+		got[key] = data
+		return true
+	})
+	assert.Equal(t, map[string][]byte{"user:1": {0x10}, "user:2": {0x20}}, got)
+}
+
+func TestCache_Range_stops_early(t *testing.T) {
+	c := New(context.Background())
+
+	c.Put("user:1", []byte{0x10})
+	c.Put("user:2", []byte{0x20})
+
+	n := 0
+	c.Range("user:", func(key string, data []byte) bool {
+		n++
+		return false
+	})
+	assert.Equal(t, 1, n)
+}
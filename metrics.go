@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// EventType identifies what happened to a key in an Event.
+type EventType int
+
+const (
+	EventPut EventType = iota
+	EventDelete
+	EventEvict
+	EventExpire
+	EventSecondaryWriteError
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventPut:
+		return "put"
+	case EventDelete:
+		return "delete"
+	case EventEvict:
+		return "evict"
+	case EventExpire:
+		return "expire"
+	case EventSecondaryWriteError:
+		return "secondary_write_error"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a single mutation of the cache, passed to the callback
+// registered with WithOnEvent.
+type Event struct {
+	Type   EventType
+	Key    string
+	Size   int
+	Reason string
+}
+
+// Stats is a snapshot of the cache's cumulative counters. Every field is
+// updated with atomic operations, so a Stats value is safe to read
+// concurrently with cache traffic but is not a point-in-time-consistent
+// transaction across fields.
+type Stats struct {
+	Hits            uint64
+	Misses          uint64
+	Evictions       uint64
+	Expirations     uint64
+	BytesIn         uint64
+	BytesOut        uint64
+	ItemCount       uint64
+	SecondaryErrors uint64
+}
+
+// WithOnEvent registers a callback invoked for every put, delete, eviction
+// and expiration. fn is called synchronously on the goroutine performing the
+// mutation, so it must not block or call back into the cache.
+func WithOnEvent(fn func(Event)) OptionFunc {
+	return func(c *Cache) {
+		c.onEvent = fn
+	}
+}
+
+// WithMetricsResetInterval makes Stats() windowed: the counters returned by
+// Stats are zeroed on this interval instead of accumulating for the life of
+// the cache. ItemCount is not reset.
+func WithMetricsResetInterval(t time.Duration) OptionFunc {
+	return func(c *Cache) {
+		c.metricsResetInterval = t
+	}
+}
+
+// Stats returns a snapshot of the cache's cumulative metrics.
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:            atomic.LoadUint64(&c.stats.Hits),
+		Misses:          atomic.LoadUint64(&c.stats.Misses),
+		Evictions:       atomic.LoadUint64(&c.stats.Evictions),
+		Expirations:     atomic.LoadUint64(&c.stats.Expirations),
+		BytesIn:         atomic.LoadUint64(&c.stats.BytesIn),
+		BytesOut:        atomic.LoadUint64(&c.stats.BytesOut),
+		ItemCount:       atomic.LoadUint64(&c.stats.ItemCount),
+		SecondaryErrors: atomic.LoadUint64(&c.stats.SecondaryErrors),
+	}
+}
+
+func (c *Cache) resetStats() {
+	atomic.StoreUint64(&c.stats.Hits, 0)
+	atomic.StoreUint64(&c.stats.Misses, 0)
+	atomic.StoreUint64(&c.stats.Evictions, 0)
+	atomic.StoreUint64(&c.stats.Expirations, 0)
+	atomic.StoreUint64(&c.stats.BytesIn, 0)
+	atomic.StoreUint64(&c.stats.BytesOut, 0)
+	atomic.StoreUint64(&c.stats.SecondaryErrors, 0)
+}
+
+// emit invokes the configured OnEvent callback, if any.
+func (c *Cache) emit(e Event) {
+	if c.onEvent != nil {
+		c.onEvent(e)
+	}
+}
+
+func (c *Cache) resetStatsLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.metricsResetInterval):
+		}
+
+		c.resetStats()
+	}
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_Stats_put_and_get_hit(t *testing.T) {
+	c := New(context.Background())
+
+	c.Put("foo", []byte("12345"))
+	_, err := c.Get("foo")
+	assert.NoError(t, err)
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(0), stats.Misses)
+	assert.Equal(t, uint64(5), stats.BytesIn)
+	assert.Equal(t, uint64(5), stats.BytesOut)
+	assert.Equal(t, uint64(1), stats.ItemCount)
+}
+
+func TestCache_Stats_get_miss(t *testing.T) {
+	c := New(context.Background())
+
+	_, err := c.Get("missing")
+	assert.Equal(t, ErrNotFound, err)
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(0), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+}
+
+func TestCache_Stats_delete_decrements_item_count(t *testing.T) {
+	c := New(context.Background())
+
+	c.Put("foo", []byte("12345"))
+	c.Delete("foo")
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(0), stats.ItemCount)
+}
+
+func TestCache_Stats_compact_counts_eviction(t *testing.T) {
+	c := New(context.Background())
+	c.sizeLimit = 10
+
+	c.Put("foo", []byte("12345"))
+	c.Put("bar", []byte("12345"))
+	c.Put("baz", []byte("12345"))
+
+	time.Sleep(time.Millisecond * 50) // time for compact to run
+
+	stats := c.Stats()
+	assert.True(t, stats.Evictions > 0)
+	assert.Equal(t, uint64(len(c.storage)), stats.ItemCount)
+}
+
+func TestCache_Stats_expired_counts_expiration(t *testing.T) {
+	c := New(context.Background(), WithCheckExpireTimeout(time.Millisecond*10), WithClearExpireTimeout(time.Millisecond*10))
+
+	c.PutWithTTL("foo", []byte("12345"), -time.Second)
+
+	time.Sleep(time.Millisecond * 50)
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(1), stats.Expirations)
+	assert.Equal(t, uint64(0), stats.ItemCount)
+}
+
+func TestCache_WithOnEvent_fires_on_put_and_delete(t *testing.T) {
+	var mu sync.Mutex
+	var events []Event
+
+	c := New(context.Background(), WithOnEvent(func(e Event) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}))
+
+	c.Put("foo", []byte("12345"))
+	c.Delete("foo")
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, EventPut, events[0].Type)
+	assert.Equal(t, "foo", events[0].Key)
+	assert.Equal(t, EventDelete, events[1].Type)
+}
+
+func TestCache_WithMetricsResetInterval_resets_counters(t *testing.T) {
+	c := New(context.Background(), WithMetricsResetInterval(time.Millisecond*20))
+
+	c.Put("foo", []byte("12345"))
+	_, _ = c.Get("foo")
+
+	assert.True(t, c.Stats().Hits > 0)
+
+	time.Sleep(time.Millisecond * 60)
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(0), stats.Hits)
+	assert.Equal(t, uint64(0), stats.BytesIn)
+
+	// ItemCount is not a rate counter and survives a reset.
+	assert.Equal(t, uint64(1), stats.ItemCount)
+}
+
+func TestEventType_String(t *testing.T) {
+	assert.Equal(t, "put", EventPut.String())
+	assert.Equal(t, "delete", EventDelete.String())
+	assert.Equal(t, "evict", EventEvict.String())
+	assert.Equal(t, "expire", EventExpire.String())
+}
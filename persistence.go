@@ -0,0 +1,578 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSegmentSizeLimit = int64(64 * 1024 * 1024)
+	defaultMergeInterval    = time.Hour
+
+	segmentFileExt = ".seg"
+	hintFileExt    = ".hint"
+
+	// recordHeaderSize is crc32(4) + tstamp(8) + ttl(8) + keysz(4) + valsz(4).
+	recordHeaderSize = 4 + 8 + 8 + 4 + 4
+
+	// hintHeaderSize is tstamp(8) + ttl(8) + offset(8) + keysz(4) + valsz(4).
+	hintHeaderSize = 8 + 8 + 8 + 4 + 4
+)
+
+// record is a single entry read back from a segment or hint file while
+// rebuilding the in-memory index. data is nil when the record came from a
+// hint file, which stores offsets but not values; valsz is always the real
+// value length, so callers must use it instead of len(data) for accounting.
+type record struct {
+	key       string
+	data      []byte
+	valsz     int
+	tombstone bool
+	ttl       time.Time
+	tstamp    time.Time
+
+	fileID int
+	offset int64
+}
+
+// persistence is the bitcask-style append-only log backing a Cache opened
+// with WithPersistence. Writes go to the active segment; older segments are
+// read-only until a merge rewrites their live records into a fresh one.
+type persistence struct {
+	mx sync.Mutex
+
+	dir              string
+	segmentSizeLimit int64
+
+	nextID     int // next id to hand out, for both rotation and merge segments
+	activeID   int
+	activeFile *os.File
+	activeSize int64
+}
+
+func openPersistence(dir string, segmentSizeLimit int64) (*persistence, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create persistence dir: %w", err)
+	}
+
+	ids, err := segmentIDs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &persistence{
+		dir:              dir,
+		segmentSizeLimit: segmentSizeLimit,
+	}
+
+	if len(ids) > 0 {
+		p.nextID = ids[len(ids)-1] + 1
+	}
+
+	if err := p.openActiveSegment(p.allocateID()); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// allocateID hands out a fresh, never-reused segment id for either the next
+// active segment (rotate) or a merge segment.
+func (p *persistence) allocateID() int {
+	id := p.nextID
+	p.nextID++
+	return id
+}
+
+func (p *persistence) openActiveSegment(id int) error {
+	f, err := os.OpenFile(p.segmentPath(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open segment %d: %w", id, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("stat segment %d: %w", id, err)
+	}
+
+	p.activeID = id
+	p.activeFile = f
+	p.activeSize = info.Size()
+
+	return nil
+}
+
+func (p *persistence) segmentPath(id int) string {
+	return filepath.Join(p.dir, fmt.Sprintf("%09d%s", id, segmentFileExt))
+}
+
+func (p *persistence) hintPath(id int) string {
+	return filepath.Join(p.dir, fmt.Sprintf("%09d%s", id, hintFileExt))
+}
+
+// segmentIDs returns the ids of every segment file in dir, sorted ascending.
+func segmentIDs(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), segmentFileExt) {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSuffix(e.Name(), segmentFileExt))
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	return ids, nil
+}
+
+// put appends a record for key to the active segment and returns its
+// location. ttl is the zero Time when the key has no expiry.
+func (p *persistence) put(key string, data []byte, ttl time.Time) (fileID int, offset int64, err error) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	buf := encodeRecord(key, data, ttl, time.Now())
+
+	offset = p.activeSize
+
+	n, err := p.activeFile.Write(buf)
+	if err != nil {
+		return 0, 0, fmt.Errorf("append record: %w", err)
+	}
+	p.activeSize += int64(n)
+	fileID = p.activeID
+
+	if p.activeSize >= p.segmentSizeLimit {
+		if err := p.rotate(); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return fileID, offset, nil
+}
+
+// delete appends a tombstone record (zero-length value) for key.
+func (p *persistence) delete(key string) error {
+	_, _, err := p.put(key, nil, time.Time{})
+	return err
+}
+
+// rotate closes the active segment and opens a fresh one. Callers must hold p.mx.
+func (p *persistence) rotate() error {
+	if err := p.activeFile.Close(); err != nil {
+		return fmt.Errorf("close segment %d: %w", p.activeID, err)
+	}
+
+	return p.openActiveSegment(p.allocateID())
+}
+
+func (p *persistence) sync() error {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	return p.activeFile.Sync()
+}
+
+func (p *persistence) close() error {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	return p.activeFile.Close()
+}
+
+// read returns the value stored at fileID/offset.
+func (p *persistence) read(fileID int, offset int64) ([]byte, error) {
+	f, err := os.Open(p.segmentPath(fileID))
+	if err != nil {
+		return nil, fmt.Errorf("open segment %d: %w", fileID, err)
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seek segment %d: %w", fileID, err)
+	}
+
+	r, _, _, _, err := decodeRecord(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("decode record at %d:%d: %w", fileID, offset, err)
+	}
+
+	return r, nil
+}
+
+// rebuild reads every segment, preferring a segment's hint file when
+// present, and returns the resulting storage map plus its total byte size.
+// Records are applied in tstamp order rather than segment-id/scan order: a
+// merge segment is allocated a higher id than the still-active segment it
+// snapshotted, but the active segment keeps taking newer writes after the
+// snapshot, so id order does not imply chronological order. The newest
+// record for a key wins; tombstones and expired TTLs drop the key.
+func (p *persistence) rebuild() (map[string]*item, int64, error) {
+	ids, err := segmentIDs(p.dir)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var all []record
+	for _, id := range ids {
+		if recs, err := p.readHint(id); err == nil {
+			all = append(all, recs...)
+			continue
+		}
+
+		recs, err := p.scanSegment(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		all = append(all, recs...)
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].tstamp.Before(all[j].tstamp)
+	})
+
+	storage := map[string]*item{}
+	var size int64
+	now := time.Now()
+
+	for _, r := range all {
+		if old, ok := storage[r.key]; ok {
+			size -= int64(old.size)
+			delete(storage, r.key)
+		}
+
+		if r.tombstone {
+			continue
+		}
+		if !r.ttl.IsZero() && r.ttl.Before(now) {
+			continue
+		}
+
+		storage[r.key] = &item{
+			key:    r.key,
+			data:   r.data,
+			size:   r.valsz,
+			ttl:    r.ttl,
+			lu:     r.tstamp,
+			fileID: r.fileID,
+			offset: r.offset,
+		}
+		size += int64(r.valsz)
+	}
+
+	return storage, size, nil
+}
+
+func (p *persistence) scanSegment(id int) ([]record, error) {
+	f, err := os.Open(p.segmentPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("open segment %d: %w", id, err)
+	}
+	defer f.Close()
+
+	var recs []record
+	r := bufio.NewReader(f)
+	var offset int64
+
+	for {
+		start := offset
+		data, key, ttl, tstamp, err := decodeRecordAt(r, &offset)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// A short or corrupt tail record means the process died mid-write;
+			// stop here rather than failing the whole rebuild.
+			break
+		}
+
+		recs = append(recs, record{
+			key:       key,
+			data:      data,
+			valsz:     len(data),
+			tombstone: data == nil,
+			ttl:       ttl,
+			tstamp:    tstamp,
+			fileID:    id,
+			offset:    start,
+		})
+	}
+
+	return recs, nil
+}
+
+// readHint loads the key index for a merged segment without scanning its
+// values.
+func (p *persistence) readHint(id int) ([]record, error) {
+	f, err := os.Open(p.hintPath(id))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var recs []record
+	r := bufio.NewReader(f)
+
+	for {
+		header := make([]byte, hintHeaderSize)
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		tstamp := int64(binary.BigEndian.Uint64(header[0:8]))
+		ttl := int64(binary.BigEndian.Uint64(header[8:16]))
+		offset := int64(binary.BigEndian.Uint64(header[16:24]))
+		keysz := binary.BigEndian.Uint32(header[24:28])
+		valsz := binary.BigEndian.Uint32(header[28:32])
+
+		key := make([]byte, keysz)
+		if _, err := io.ReadFull(r, key); err != nil {
+			return nil, err
+		}
+
+		recs = append(recs, record{
+			key:       string(key),
+			valsz:     int(valsz),
+			tombstone: valsz == 0,
+			ttl:       timeFromUnixNano(ttl),
+			tstamp:    timeFromUnixNano(tstamp),
+			fileID:    id,
+			offset:    offset,
+		})
+	}
+
+	return recs, nil
+}
+
+// writeHint writes the offset index for the given already-written segment.
+func (p *persistence) writeHint(id int, recs []record) error {
+	f, err := os.OpenFile(p.hintPath(id), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create hint %d: %w", id, err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, r := range recs {
+		header := make([]byte, hintHeaderSize)
+		binary.BigEndian.PutUint64(header[0:8], uint64(unixNano(r.tstamp)))
+		binary.BigEndian.PutUint64(header[8:16], uint64(unixNano(r.ttl)))
+		binary.BigEndian.PutUint64(header[16:24], uint64(r.offset))
+		binary.BigEndian.PutUint32(header[24:28], uint32(len(r.key)))
+		binary.BigEndian.PutUint32(header[28:32], uint32(len(r.data)))
+
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+		if _, err := w.WriteString(r.key); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// merge rewrites the currently live keys into a fresh segment, removes the
+// segments it replaces, and writes a hint file so a future restart can skip
+// re-scanning the merged segment's values. keys is a snapshot of the live key
+// set taken under the cache's own lock by mergeLoop; merge re-reads each
+// key's current value from the cache immediately before writing it, so a
+// concurrent Put landing between the snapshot and the write is never lost.
+func (p *persistence) merge(c *Cache, keys []string) error {
+	ids, err := segmentIDs(p.dir)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	p.mx.Lock()
+	mergeID := p.allocateID()
+	currentActiveID := p.activeID
+	p.mx.Unlock()
+
+	f, err := os.OpenFile(p.segmentPath(mergeID), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create merge segment %d: %w", mergeID, err)
+	}
+
+	var offset int64
+	written := make([]record, 0, len(keys))
+	for _, key := range keys {
+		r, ok := c.currentRecord(key)
+		if !ok {
+			continue
+		}
+
+		buf := encodeRecord(r.key, r.data, r.ttl, r.tstamp)
+		if _, err := f.Write(buf); err != nil {
+			f.Close()
+			return fmt.Errorf("write merge segment %d: %w", mergeID, err)
+		}
+		written = append(written, record{key: r.key, data: r.data, ttl: r.ttl, tstamp: r.tstamp, fileID: mergeID, offset: offset})
+		offset += int64(len(buf))
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close merge segment %d: %w", mergeID, err)
+	}
+
+	if err := p.writeHint(mergeID, written); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if id == currentActiveID || id == mergeID {
+			continue
+		}
+		os.Remove(p.segmentPath(id))
+		os.Remove(p.hintPath(id))
+	}
+
+	return nil
+}
+
+func encodeRecord(key string, data []byte, ttl, tstamp time.Time) []byte {
+	buf := make([]byte, recordHeaderSize+len(key)+len(data))
+
+	binary.BigEndian.PutUint64(buf[4:12], uint64(unixNano(tstamp)))
+	binary.BigEndian.PutUint64(buf[12:20], uint64(unixNano(ttl)))
+	binary.BigEndian.PutUint32(buf[20:24], uint32(len(key)))
+	binary.BigEndian.PutUint32(buf[24:28], uint32(len(data)))
+	copy(buf[recordHeaderSize:], key)
+	copy(buf[recordHeaderSize+len(key):], data)
+
+	crc := crc32.ChecksumIEEE(buf[4:])
+	binary.BigEndian.PutUint32(buf[0:4], crc)
+
+	return buf
+}
+
+func decodeRecord(r io.Reader) (data []byte, key string, ttl time.Time, tstamp time.Time, err error) {
+	var offset int64
+	data, key, ttl, tstamp, err = decodeRecordAt(r, &offset)
+	return data, key, ttl, tstamp, err
+}
+
+func decodeRecordAt(r io.Reader, offset *int64) (data []byte, key string, ttl time.Time, tstamp time.Time, err error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return nil, "", time.Time{}, time.Time{}, err
+	}
+
+	crc := binary.BigEndian.Uint32(header[0:4])
+	tstampNano := int64(binary.BigEndian.Uint64(header[4:12]))
+	ttlNano := int64(binary.BigEndian.Uint64(header[12:20]))
+	keysz := binary.BigEndian.Uint32(header[20:24])
+	valsz := binary.BigEndian.Uint32(header[24:28])
+
+	body := make([]byte, keysz+valsz)
+	if _, err = io.ReadFull(r, body); err != nil {
+		return nil, "", time.Time{}, time.Time{}, err
+	}
+
+	if crc32.ChecksumIEEE(append(header[4:], body...)) != crc {
+		return nil, "", time.Time{}, time.Time{}, fmt.Errorf("crc mismatch")
+	}
+
+	*offset += int64(recordHeaderSize) + int64(keysz) + int64(valsz)
+
+	key = string(body[:keysz])
+	if valsz > 0 {
+		data = body[keysz:]
+	}
+
+	return data, key, timeFromUnixNano(ttlNano), timeFromUnixNano(tstampNano), nil
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func timeFromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// mergeLoop periodically rewrites live records into a fresh segment so disk
+// usage doesn't grow without bound from overwritten and deleted keys.
+func (c *Cache) mergeLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.mergeInterval):
+		}
+
+		c.mx.RLock()
+		keys := make([]string, 0, len(c.storage))
+		for key := range c.storage {
+			keys = append(keys, key)
+		}
+		c.mx.RUnlock()
+
+		if err := c.persist.merge(c, keys); err != nil {
+			continue
+		}
+	}
+}
+
+// currentRecord returns key's record as it stands right now, re-reading its
+// value from disk if it hasn't been loaded into memory yet. It is used by
+// merge to read each key immediately before writing it into the merge
+// segment, rather than from a stale snapshot, so a write that lands between
+// the snapshot and the merge is not reverted.
+func (c *Cache) currentRecord(key string) (record, bool) {
+	c.mx.RLock()
+	i, ok := c.storage[key]
+	if !ok {
+		c.mx.RUnlock()
+		return record{}, false
+	}
+	data, ttl, tstamp, size, fileID, offset := i.data, i.ttl, i.lu, i.size, i.fileID, i.offset
+	c.mx.RUnlock()
+
+	if data == nil && size > 0 {
+		// item was rebuilt from a hint file and its value was never loaded
+		// into memory; fetch it so the merge doesn't turn it into a
+		// tombstone.
+		if d, err := c.persist.read(fileID, offset); err == nil {
+			data = d
+		}
+	}
+
+	return record{key: key, data: data, ttl: ttl, tstamp: tstamp}, true
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_WithPersistence_survives_restart(t *testing.T) {
+	dir := t.TempDir()
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	c := New(ctx, WithPersistence(dir))
+
+	c.Put("foo", []byte("bar"))
+	c.PutWithTTL("baz", []byte("qux"), time.Hour)
+
+	assert.NoError(t, c.Sync())
+	ctxCancel()
+
+	time.Sleep(time.Millisecond * 50)
+
+	c2 := New(context.Background(), WithPersistence(dir))
+
+	data, err := c2.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), data)
+
+	data, err = c2.Get("baz")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("qux"), data)
+}
+
+func TestCache_WithPersistence_delete_tombstone_survives_restart(t *testing.T) {
+	dir := t.TempDir()
+
+	c := New(context.Background(), WithPersistence(dir))
+
+	c.Put("foo", []byte("bar"))
+	c.Delete("foo")
+
+	c2 := New(context.Background(), WithPersistence(dir))
+
+	_, err := c2.Get("foo")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestCache_WithPersistence_expired_dropped_on_rebuild(t *testing.T) {
+	dir := t.TempDir()
+
+	c := New(context.Background(), WithPersistence(dir))
+
+	c.PutWithTTL("foo", []byte("bar"), time.Millisecond*10)
+	time.Sleep(time.Millisecond * 20)
+
+	c2 := New(context.Background(), WithPersistence(dir))
+
+	_, err := c2.Get("foo")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestCache_WithPersistence_eviction_tombstone_survives_restart(t *testing.T) {
+	dir := t.TempDir()
+
+	c := New(context.Background(), WithPersistence(dir))
+	c.sizeLimit = 10
+
+	c.Put("foo", []byte("12345"))
+	c.Put("bar", []byte("12345"))
+	c.Put("baz", []byte("12345"))
+
+	time.Sleep(time.Millisecond * 50) // time for compact to run and evict "foo"
+
+	_, ok := c.storage["foo"]
+	assert.False(t, ok)
+
+	c2 := New(context.Background(), WithPersistence(dir))
+
+	_, err := c2.Get("foo")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestCache_WithPersistence_write_after_merge_survives_restart(t *testing.T) {
+	dir := t.TempDir()
+
+	c := New(context.Background(), WithPersistence(dir))
+
+	c.Put("a", []byte("v1"))
+
+	assert.NoError(t, c.persist.merge(c, c.Keys()))
+
+	c.Put("a", []byte("v2"))
+
+	assert.NoError(t, c.Sync())
+
+	c2 := New(context.Background(), WithPersistence(dir))
+
+	data, err := c2.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v2"), data)
+}
+
+func TestCache_Sync_without_persistence(t *testing.T) {
+	c := New(context.Background())
+
+	assert.Equal(t, ErrNoPersistence, c.Sync())
+}
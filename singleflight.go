@@ -0,0 +1,41 @@
+package cache
+
+import "sync"
+
+// call represents an in-flight or completed GetCallback invocation shared
+// between concurrent GetOrNew/GetOrNewWithTTL misses on the same key.
+type call struct {
+	wg   sync.WaitGroup
+	data []byte
+	err  error
+}
+
+// startCall registers the caller as the owner of key's in-flight call if
+// none is running yet, or returns the existing one to wait on.
+func (c *Cache) startCall(key string) (cl *call, owner bool) {
+	c.sfMx.Lock()
+	defer c.sfMx.Unlock()
+
+	if cl, ok := c.sfCalls[key]; ok {
+		return cl, false
+	}
+
+	cl = &call{}
+	cl.wg.Add(1)
+	c.sfCalls[key] = cl
+
+	return cl, true
+}
+
+// finishCall publishes the owner's result to any waiters and retires key's
+// in-flight call.
+func (c *Cache) finishCall(key string, cl *call, data []byte, err error) {
+	cl.data = data
+	cl.err = err
+
+	c.sfMx.Lock()
+	delete(c.sfCalls, key)
+	c.sfMx.Unlock()
+
+	cl.wg.Done()
+}
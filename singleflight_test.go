@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_GetOrNew_singleflight_coalesces(t *testing.T) {
+	c := New(context.Background())
+
+	var calls int32
+	cb := func(key string) ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(time.Millisecond * 50)
+		return []byte("value"), nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]byte, 10)
+	errs := make([]error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = c.GetOrNew("foo", cb)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, []byte("value"), results[i])
+	}
+}
+
+func TestCache_GetOrNew_singleflight_propagates_error(t *testing.T) {
+	c := New(context.Background())
+
+	cb := func(key string) ([]byte, error) {
+		time.Sleep(time.Millisecond * 50)
+		return nil, fmt.Errorf("boom")
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = c.GetOrNew("foo", cb)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 5; i++ {
+		assert.Error(t, errs[i])
+		assert.Equal(t, "boom", errs[i].Error())
+	}
+
+	_, ok := c.storage["foo"]
+	assert.False(t, ok)
+}
+
+func TestCache_GetOrNew_singleflight_disabled(t *testing.T) {
+	c := New(context.Background(), WithSingleflight(false))
+
+	var calls int32
+	cb := func(key string) ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(time.Millisecond * 50)
+		return []byte("value"), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.GetOrNew("foo", cb)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+}